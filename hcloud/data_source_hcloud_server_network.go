@@ -0,0 +1,172 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+// dataSourceServerNetwork looks up a single server<->network attachment,
+// wrapping the same lookup resourceServerNetworkRead uses plus two lookup
+// paths the resource has no need for: by private IP and by MAC address, for
+// callers that only know a network and a well-known address (e.g. pinning a
+// hcloud_floating_ip or hcloud_load_balancer_target to whichever server
+// currently holds that IP).
+func dataSourceServerNetwork() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceServerNetworkRead,
+		Schema: map[string]*schema.Schema{
+			"network_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+			},
+			"server_id": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"ip": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"mac_address": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"subnet_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"alias_ips": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceServerNetworkRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*hcloud.Client)
+
+	networkID := int64(d.Get("network_id").(int))
+	network, _, err := client.Network.GetByID(ctx, networkID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if network == nil {
+		return diag.Errorf("network %d not found", networkID)
+	}
+
+	serverIDRaw, serverIDSet := d.GetOk("server_id")
+	ipRaw, ipSet := d.GetOk("ip")
+	macRaw, macSet := d.GetOk("mac_address")
+	if lookupsSet(serverIDSet, ipSet, macSet) != 1 {
+		return diag.Errorf("exactly one of server_id, ip, or mac_address must be set")
+	}
+
+	var server *hcloud.Server
+	var pn *hcloud.ServerPrivateNet
+
+	switch {
+	case serverIDSet:
+		server, _, err = client.Server.GetByID(ctx, int64(serverIDRaw.(int)))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if server != nil {
+			pn = findPrivateNet(server, networkID)
+		}
+	case ipSet:
+		server, pn, err = findServerInNetworkByAttribute(ctx, client, networkID, func(candidate *hcloud.ServerPrivateNet) bool {
+			return candidate.IP.Equal(net.ParseIP(ipRaw.(string)))
+		})
+	case macSet:
+		server, pn, err = findServerInNetworkByAttribute(ctx, client, networkID, func(candidate *hcloud.ServerPrivateNet) bool {
+			return candidate.MACAddress == macRaw.(string)
+		})
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if server == nil || pn == nil {
+		return diag.Errorf("no server attachment to network %d matched the given lookup", networkID)
+	}
+
+	d.SetId(generateServerNetworkID(server, network))
+	d.Set("server_id", server.ID)
+	d.Set("network_id", network.ID)
+	d.Set("ip", pn.IP.String())
+	d.Set("mac_address", pn.MACAddress)
+	d.Set("alias_ips", ipsToStrings(pn.Aliases))
+	if subnetID, ok := subnetIDForIP(network, pn.IP); ok {
+		d.Set("subnet_id", subnetID)
+	}
+
+	return nil
+}
+
+// findPrivateNet returns server's ServerPrivateNet entry for networkID, or
+// nil if the server isn't attached to it.
+func findPrivateNet(server *hcloud.Server, networkID int64) *hcloud.ServerPrivateNet {
+	for i := range server.PrivateNet {
+		if server.PrivateNet[i].Network.ID == networkID {
+			return &server.PrivateNet[i]
+		}
+	}
+	return nil
+}
+
+// findServerInNetworkByAttribute scans every server for one attached to
+// networkID whose ServerPrivateNet matches predicate. The Hetzner API has no
+// server-side filter for "server by private IP/MAC", so this is a full
+// listing; callers should prefer the server_id lookup when the server is
+// already known.
+func findServerInNetworkByAttribute(ctx context.Context, client *hcloud.Client, networkID int64, predicate func(*hcloud.ServerPrivateNet) bool) (*hcloud.Server, *hcloud.ServerPrivateNet, error) {
+	servers, err := client.Server.All(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, srv := range servers {
+		pn := findPrivateNet(srv, networkID)
+		if pn != nil && predicate(pn) {
+			return srv, pn, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// subnetIDForIP finds the subnet of network that contains ip, returning the
+// same "<network id>-<subnet ip range>" id parseNetworkSubnetID expects.
+func subnetIDForIP(network *hcloud.Network, ip net.IP) (string, bool) {
+	for _, subnet := range network.Subnets {
+		if subnet.IPRange != nil && subnet.IPRange.Contains(ip) {
+			return fmt.Sprintf("%d-%s", network.ID, subnet.IPRange.String()), true
+		}
+	}
+	return "", false
+}
+
+func ipsToStrings(ips []net.IP) []string {
+	out := make([]string, len(ips))
+	for i, ip := range ips {
+		out[i] = ip.String()
+	}
+	return out
+}
+
+func lookupsSet(flags ...bool) int {
+	n := 0
+	for _, f := range flags {
+		if f {
+			n++
+		}
+	}
+	return n
+}
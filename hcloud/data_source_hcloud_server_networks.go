@@ -0,0 +1,95 @@
+package hcloud
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+// dataSourceServerNetworks enumerates every server attached to a network, so
+// a module holding only a network_id can build a for_each over its
+// attachments without knowing the server IDs up front.
+func dataSourceServerNetworks() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceServerNetworksRead,
+		Schema: map[string]*schema.Schema{
+			"network_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+			},
+			"server_networks": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"server_id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"ip": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"mac_address": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"subnet_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"alias_ips": {
+							Type:     schema.TypeSet,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceServerNetworksRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*hcloud.Client)
+
+	networkID := int64(d.Get("network_id").(int))
+	network, _, err := client.Network.GetByID(ctx, networkID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if network == nil {
+		return diag.Errorf("network %d not found", networkID)
+	}
+
+	servers, err := client.Server.All(ctx)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	serverNetworks := make([]map[string]interface{}, 0, len(servers))
+	for _, srv := range servers {
+		pn := findPrivateNet(srv, networkID)
+		if pn == nil {
+			continue
+		}
+		entry := map[string]interface{}{
+			"server_id":   srv.ID,
+			"ip":          pn.IP.String(),
+			"mac_address": pn.MACAddress,
+			"alias_ips":   ipsToStrings(pn.Aliases),
+		}
+		if subnetID, ok := subnetIDForIP(network, pn.IP); ok {
+			entry["subnet_id"] = subnetID
+		}
+		serverNetworks = append(serverNetworks, entry)
+	}
+
+	d.SetId(strconv.FormatInt(networkID, 10))
+	d.Set("server_networks", serverNetworks)
+
+	return nil
+}
@@ -0,0 +1,751 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+func resourceServer() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceServerCreate,
+		ReadContext:   resourceServerRead,
+		UpdateContext: resourceServerUpdate,
+		DeleteContext: resourceServerDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+		// SchemaVersion bumped for the same reason as resourceServerNetwork's:
+		// hcloud-go v2 widened IDs to int64. There is no stored attribute shape
+		// to migrate here, so no StateUpgraders are registered.
+		SchemaVersion: 1,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"server_type": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			// ForceNew: the API has no "change image" operation short of a
+			// destructive rebuild, which isn't wired up here, so a new image is
+			// only ever applied to a freshly created server.
+			"image": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"location": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"user_data": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"ssh_keys": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"keep_disk": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"backups": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"labels": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"delete_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			"ipv4_address": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"ipv6_address": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			// network declares a private-network attachment that is created and
+			// destroyed together with the server. It is an alternative to managing
+			// attachments as standalone hcloud_server_network resources; see
+			// serverNetworkBlockSchema for the field-by-field mapping. A given
+			// (network_id, server) pair must be managed by exactly one of the two -
+			// attaching the same network both inline and via hcloud_server_network
+			// will race on create and is rejected unless the other side's adopt
+			// flag is set; see the ownership check in attachInlineServerNetworks
+			// and resourceServerNetworkCreate.
+			//
+			// This is a TypeList, not a TypeSet: a set's element hash is computed
+			// from every field including the Computed ones (ip, mac_address), so
+			// a set would hash differently at plan time (ip/mac unknown) than at
+			// apply time (ip/mac populated), producing a diff that never settles.
+			"network": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: serverNetworkBlockSchema(),
+				},
+			},
+		},
+	}
+}
+
+// serverNetworkBlockSchema is shared by the "network" block on hcloud_server
+// and mirrors the attributes exposed by the standalone hcloud_server_network
+// resource (see resource_hcloud_server_network.go). The two are NOT
+// state-compatible: `terraform state mv` moves a whole resource instance, it
+// cannot merge a standalone hcloud_server_network's state into one element
+// of this nested list. Moving an existing attachment from the standalone
+// resource to an inline block (or back) is a manual, apply-time operation:
+//  1. Remove the hcloud_server_network resource from config and run
+//     `terraform state rm` on it, without ever calling destroy - the
+//     attachment must stay live on the API side.
+//  2. Add the matching "network" block to the hcloud_server config, with
+//     adopt = true so the ownership check below doesn't reject it.
+//  3. Apply; attachServerToNetwork treats an already-attached network as a
+//     no-op, so this is a refresh with no API side effects.
+func serverNetworkBlockSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"network_id": {
+			Type:     schema.TypeInt,
+			Optional: true,
+		},
+		"subnet_id": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		// Not ForceNew: SDKv2 has no way to force replacement of just one
+		// element of a nested list, so ForceNew here would replace the whole
+		// hcloud_server instead of just this attachment. reconcileInlineServerNetworks
+		// instead detaches and reattaches with the new ip in place, the same
+		// operation pair used to resolve a TypeList diff on this element already.
+		"ip": {
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+		},
+		"alias_ips": {
+			Type:     schema.TypeSet,
+			Optional: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"mac_address": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		// adopt must be set when this block's network is already attached to
+		// the server by something other than this "network" block, e.g. a
+		// standalone hcloud_server_network resource. Without it,
+		// attachInlineServerNetworks refuses to take over an attachment it
+		// did not create. Mirrors hcloud_server_network's own adopt flag.
+		"adopt": {
+			Type:     schema.TypeBool,
+			Optional: true,
+		},
+	}
+}
+
+// validateServerNetworkBlock rejects a "network" block that sets neither or
+// both of network_id/subnet_id - left unchecked, an empty block silently
+// resolves networkID to 0 and attempts to attach to network 0 instead of
+// failing with a clear error.
+func validateServerNetworkBlock(block map[string]interface{}) error {
+	_, nwIDSet := block["network_id"].(int)
+	nwIDSet = nwIDSet && block["network_id"].(int) != 0
+	subnetID, _ := block["subnet_id"].(string)
+	snIDSet := subnetID != ""
+	if nwIDSet == snIDSet {
+		return fmt.Errorf("network block must set exactly one of network_id or subnet_id")
+	}
+	return nil
+}
+
+func resourceServerCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*hcloud.Client)
+
+	sshKeys, err := resolveServerSSHKeys(ctx, client, d.Get("ssh_keys").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	opts := hcloud.ServerCreateOpts{
+		Name:       d.Get("name").(string),
+		ServerType: &hcloud.ServerType{Name: d.Get("server_type").(string)},
+		Image:      &hcloud.Image{Name: d.Get("image").(string)},
+		UserData:   d.Get("user_data").(string),
+		SSHKeys:    sshKeys,
+		Labels:     expandServerLabels(d),
+	}
+	if v, ok := d.GetOk("location"); ok {
+		opts.Location = &hcloud.Location{Name: v.(string)}
+	}
+	if v, ok := d.GetOk("datacenter"); ok {
+		opts.Datacenter = &hcloud.Datacenter{Name: v.(string)}
+	}
+
+	res, _, err := client.Server.Create(ctx, opts)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(generateServerID(res.Server))
+
+	if err := attachInlineServerNetworks(ctx, client, res.Server, d.Get("network").([]interface{}), d.Timeout(schema.TimeoutCreate)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if protect := d.Get("delete_protection").(bool); protect {
+		if err := waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutCreate), func() (*hcloud.Action, error) {
+			a, _, err := client.Server.ChangeProtection(ctx, res.Server, hcloud.ServerChangeProtectionOpts{Delete: &protect})
+			return a, err
+		}); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceServerRead(ctx, d, m)
+}
+
+// resolveServerSSHKeys resolves ssh_keys entries (each either a numeric ID or
+// a name) to the hcloud_ssh_key the API expects at create time, the same
+// "accept an ID or a name" convenience hcloud-go's SSHKey.Get affords.
+func resolveServerSSHKeys(ctx context.Context, client *hcloud.Client, raw []interface{}) ([]*hcloud.SSHKey, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	keys := make([]*hcloud.SSHKey, 0, len(raw))
+	for _, v := range raw {
+		idOrName := v.(string)
+		key, _, err := client.SSHKey.Get(ctx, idOrName)
+		if err != nil {
+			return nil, fmt.Errorf("ssh key %q: %v", idOrName, err)
+		}
+		if key == nil {
+			return nil, fmt.Errorf("ssh key %q not found", idOrName)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func expandServerLabels(d *schema.ResourceData) map[string]string {
+	raw := d.Get("labels").(map[string]interface{})
+	labels := make(map[string]string, len(raw))
+	for k, v := range raw {
+		labels[k] = v.(string)
+	}
+	return labels
+}
+
+// mergeServerLabels returns the user-configured "labels" in d plus any
+// hcloud-router/* keys found in current (the server's actual, current label
+// set), carried over untouched; every other key in current is dropped.
+// Hetzner's label update is a full replace, not a merge, so
+// writing expandServerLabels(d) alone would wipe out whatever router
+// candidacy labels hcloud_server_network_router.go has set on this same
+// server - the same reason setServerSchema filters those keys back out
+// before storing "labels" in state (filterManagedServerLabels).
+func mergeServerLabels(current map[string]string, d *schema.ResourceData) map[string]string {
+	labels := make(map[string]string, len(current))
+	for k, v := range current {
+		if strings.HasPrefix(k, routerLabelPrefix+"/") {
+			labels[k] = v
+		}
+	}
+	for k, v := range expandServerLabels(d) {
+		labels[k] = v
+	}
+	return labels
+}
+
+func resourceServerRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*hcloud.Client)
+
+	server, err := lookupServerID(ctx, d.Id(), client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if server == nil {
+		log.Printf("[WARN] Server (%s) not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	setServerSchema(d, server)
+	return nil
+}
+
+func resourceServerUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*hcloud.Client)
+
+	server, err := lookupServerID(ctx, d.Id(), client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if server == nil {
+		log.Printf("[WARN] Server (%s) not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if d.HasChange("network") {
+		oldNetworks, newNetworks := d.GetChange("network")
+		if err := reconcileInlineServerNetworks(ctx, client, server, oldNetworks.([]interface{}), newNetworks.([]interface{}), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChange("name") || d.HasChange("labels") {
+		opts := hcloud.ServerUpdateOpts{
+			Name:   d.Get("name").(string),
+			Labels: mergeServerLabels(server.Labels, d),
+		}
+		if _, _, err := client.Server.Update(ctx, server, opts); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChange("delete_protection") {
+		protect := d.Get("delete_protection").(bool)
+		err := waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutUpdate), func() (*hcloud.Action, error) {
+			a, _, err := client.Server.ChangeProtection(ctx, server, hcloud.ServerChangeProtectionOpts{Delete: &protect})
+			return a, err
+		})
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChange("server_type") {
+		if err := resizeServer(ctx, client, server, d); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceServerRead(ctx, d, m)
+}
+
+// resizeServer changes server to the server_type now in config, powering it
+// off and back on around the change if it was running - the API only
+// accepts ChangeType while the server is off. keep_disk maps to UpgradeDisk:
+// by default a type change upgrades the disk along with the rest of the
+// server, but some type changes (e.g. within the same disk class) allow
+// skipping that to keep the existing disk image, which keep_disk opts into.
+func resizeServer(ctx context.Context, client *hcloud.Client, server *hcloud.Server, d *schema.ResourceData) error {
+	wasRunning := server.Status == hcloud.ServerStatusRunning
+
+	if wasRunning {
+		err := waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutUpdate), func() (*hcloud.Action, error) {
+			a, _, err := client.Server.Poweroff(ctx, server)
+			return a, err
+		})
+		if err != nil {
+			return fmt.Errorf("power off server for resize: %v", err)
+		}
+	}
+
+	opts := hcloud.ServerChangeTypeOpts{
+		ServerType:  &hcloud.ServerType{Name: d.Get("server_type").(string)},
+		UpgradeDisk: !d.Get("keep_disk").(bool),
+	}
+	err := waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutUpdate), func() (*hcloud.Action, error) {
+		a, _, err := client.Server.ChangeType(ctx, server, opts)
+		return a, err
+	})
+	if err != nil {
+		return fmt.Errorf("change server type: %v", err)
+	}
+
+	if wasRunning {
+		err := waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutUpdate), func() (*hcloud.Action, error) {
+			a, _, err := client.Server.Poweron(ctx, server)
+			return a, err
+		})
+		if err != nil {
+			return fmt.Errorf("power on server after resize: %v", err)
+		}
+	}
+	return nil
+}
+
+func resourceServerDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*hcloud.Client)
+
+	server, err := lookupServerID(ctx, d.Id(), client)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if server == nil {
+		return nil
+	}
+
+	// Inline network attachments are dropped by the Hetzner API as part of
+	// server deletion, so there is nothing to detach explicitly here.
+	_, err = client.Server.Delete(ctx, server)
+	if hcloud.IsError(err, hcloud.ErrorCodeNotFound) {
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// attachInlineServerNetworks attaches every network block present at create
+// time. It reuses attachServerToNetwork from resource_hcloud_server_network.go
+// so inline and standalone attachments go through the same waiter.
+func attachInlineServerNetworks(ctx context.Context, client *hcloud.Client, server *hcloud.Server, raw []interface{}, timeout time.Duration) error {
+	for _, item := range raw {
+		block := item.(map[string]interface{})
+		if err := validateServerNetworkBlock(block); err != nil {
+			return err
+		}
+
+		networkID, err := resolveInlineNetworkBlockID(block)
+		if err != nil {
+			return err
+		}
+		network := &hcloud.Network{ID: networkID}
+
+		if err := ensureInlineNetworkNotAdopted(ctx, client, server, networkID, block["adopt"].(bool)); err != nil {
+			return err
+		}
+
+		var ip net.IP
+		if v, ok := block["ip"].(string); ok && v != "" {
+			ip = net.ParseIP(v)
+		}
+		var aliasIPs []net.IP
+		for _, raw := range block["alias_ips"].(*schema.Set).List() {
+			aliasIPs = append(aliasIPs, net.ParseIP(raw.(string)))
+		}
+
+		if err := attachServerToNetwork(ctx, client, server, network, ip, aliasIPs, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveInlineNetworkBlockID returns the network a "network" block refers
+// to, resolving subnet_id to its owning network ID the same way
+// resourceServerNetworkCreate does for the standalone resource. Every caller
+// that keys state off a block's network - attachInlineServerNetworks and
+// reconcileInlineServerNetworks - must go through this rather than reading
+// block["network_id"] directly, or a subnet_id-only block (network_id left
+// unset) keys into their maps as network 0 instead of its real network.
+func resolveInlineNetworkBlockID(block map[string]interface{}) (int64, error) {
+	networkID := int64(block["network_id"].(int))
+	if subnetID, ok := block["subnet_id"].(string); ok && subnetID != "" {
+		nwID, _, err := parseNetworkSubnetID(subnetID)
+		if err != nil {
+			return 0, err
+		}
+		networkID = int64(nwID)
+	}
+	return networkID, nil
+}
+
+// ensureInlineNetworkNotAdopted is the mirror image of the ownership check in
+// resourceServerNetworkCreate: a "network" block attaching inline must not
+// silently take over an attachment already owned by a standalone
+// hcloud_server_network resource. attachServerToNetwork treats an
+// already-attached network as a no-op, so without this check the two
+// resources would end up managing the same attachment with no warning at
+// all.
+func ensureInlineNetworkNotAdopted(ctx context.Context, client *hcloud.Client, server *hcloud.Server, networkID int64, adopt bool) error {
+	if adopt {
+		return nil
+	}
+	full, _, err := client.Server.GetByID(ctx, server.ID)
+	if err != nil {
+		return err
+	}
+	if full == nil {
+		return nil
+	}
+	for _, pn := range full.PrivateNet {
+		if pn.Network.ID == networkID {
+			return fmt.Errorf("server %d is already attached to network %d (likely via a standalone hcloud_server_network resource); set network.adopt = true to manage it with this \"network\" block instead", server.ID, networkID)
+		}
+	}
+	return nil
+}
+
+// inlineServerNetworkDiff classifies the network IDs in an old/new pair of
+// "network" block lists, without touching the API - kept separate from
+// reconcileInlineServerNetworks so the classification can be unit tested on
+// its own.
+type inlineServerNetworkDiff struct {
+	detach []int64 // present in old, absent from new
+	attach []int64 // present in new, absent from old
+	update []int64 // present in both; alias_ips (and any other field) may differ
+}
+
+func diffInlineServerNetworks(oldByNetwork, newByNetwork map[int64]map[string]interface{}) inlineServerNetworkDiff {
+	var diff inlineServerNetworkDiff
+	for networkID := range oldByNetwork {
+		if _, ok := newByNetwork[networkID]; !ok {
+			diff.detach = append(diff.detach, networkID)
+		}
+	}
+	for networkID := range newByNetwork {
+		if _, existed := oldByNetwork[networkID]; existed {
+			diff.update = append(diff.update, networkID)
+		} else {
+			diff.attach = append(diff.attach, networkID)
+		}
+	}
+	return diff
+}
+
+// reconcileInlineServerNetworks diffs the old and new network block lists and
+// attaches/detaches/updates alias IPs as needed, rather than tearing down and
+// recreating every attachment on any change to the list.
+func reconcileInlineServerNetworks(ctx context.Context, client *hcloud.Client, server *hcloud.Server, oldNetworks, newNetworks []interface{}, timeout time.Duration) error {
+	oldByNetwork := map[int64]map[string]interface{}{}
+	for _, item := range oldNetworks {
+		block := item.(map[string]interface{})
+		networkID, err := resolveInlineNetworkBlockID(block)
+		if err != nil {
+			return err
+		}
+		oldByNetwork[networkID] = block
+	}
+	newByNetwork := map[int64]map[string]interface{}{}
+	for _, item := range newNetworks {
+		block := item.(map[string]interface{})
+		if err := validateServerNetworkBlock(block); err != nil {
+			return err
+		}
+		networkID, err := resolveInlineNetworkBlockID(block)
+		if err != nil {
+			return err
+		}
+		newByNetwork[networkID] = block
+	}
+
+	diff := diffInlineServerNetworks(oldByNetwork, newByNetwork)
+
+	for _, networkID := range diff.detach {
+		network := &hcloud.Network{ID: networkID}
+		err := waitForServerNetworkAction(ctx, client, timeout, func() (*hcloud.Action, error) {
+			a, _, err := client.Server.DetachFromNetwork(ctx, server, hcloud.ServerDetachFromNetworkOpts{Network: network})
+			return a, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, networkID := range diff.attach {
+		block := newByNetwork[networkID]
+		network := &hcloud.Network{ID: networkID}
+
+		if err := ensureInlineNetworkNotAdopted(ctx, client, server, networkID, block["adopt"].(bool)); err != nil {
+			return err
+		}
+
+		var ip net.IP
+		if v, ok := block["ip"].(string); ok && v != "" {
+			ip = net.ParseIP(v)
+		}
+		var aliasIPs []net.IP
+		for _, raw := range block["alias_ips"].(*schema.Set).List() {
+			aliasIPs = append(aliasIPs, net.ParseIP(raw.(string)))
+		}
+		if err := attachServerToNetwork(ctx, client, server, network, ip, aliasIPs, timeout); err != nil {
+			return err
+		}
+	}
+
+	for _, networkID := range diff.update {
+		oldBlock := oldByNetwork[networkID]
+		newBlock := newByNetwork[networkID]
+		network := &hcloud.Network{ID: networkID}
+
+		// The API has no "change private IP" operation, so a changed ip is
+		// handled by detaching and reattaching with the new one - the same
+		// pair of calls diff.attach/diff.detach already use elsewhere in this
+		// function - rather than by forcing a whole new hcloud_server.
+		if oldBlock["ip"].(string) != newBlock["ip"].(string) {
+			err := waitForServerNetworkAction(ctx, client, timeout, func() (*hcloud.Action, error) {
+				a, _, err := client.Server.DetachFromNetwork(ctx, server, hcloud.ServerDetachFromNetworkOpts{Network: network})
+				return a, err
+			})
+			if err != nil {
+				return err
+			}
+
+			var ip net.IP
+			if v, ok := newBlock["ip"].(string); ok && v != "" {
+				ip = net.ParseIP(v)
+			}
+			var aliasIPs []net.IP
+			for _, raw := range newBlock["alias_ips"].(*schema.Set).List() {
+				aliasIPs = append(aliasIPs, net.ParseIP(raw.(string)))
+			}
+			if err := attachServerToNetwork(ctx, client, server, network, ip, aliasIPs, timeout); err != nil {
+				return err
+			}
+			continue
+		}
+
+		opts := hcloud.ServerChangeAliasIPsOpts{Network: network}
+		for _, raw := range newBlock["alias_ips"].(*schema.Set).List() {
+			opts.AliasIPs = append(opts.AliasIPs, net.ParseIP(raw.(string)))
+		}
+		err := waitForServerNetworkAction(ctx, client, timeout, func() (*hcloud.Action, error) {
+			a, _, err := client.Server.ChangeAliasIPs(ctx, server, opts)
+			return a, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flattenServerNetworks returns server's private network attachments ordered
+// to match the "network" blocks already in d rather than server.PrivateNet's
+// API order. "network" is a TypeList (see the comment on its schema entry),
+// so element order is diff-significant: reading it back in whatever order
+// the API happens to return PrivateNet would produce a plan that never
+// converges even though nothing actually changed.
+func flattenServerNetworks(d *schema.ResourceData, server *hcloud.Server) []map[string]interface{} {
+	byNetwork := make(map[int64]*hcloud.ServerPrivateNet, len(server.PrivateNet))
+	for i := range server.PrivateNet {
+		byNetwork[server.PrivateNet[i].Network.ID] = &server.PrivateNet[i]
+	}
+
+	configured := d.Get("network").([]interface{})
+	networks := make([]map[string]interface{}, 0, len(server.PrivateNet))
+	seen := make(map[int64]bool, len(server.PrivateNet))
+
+	for _, item := range configured {
+		block := item.(map[string]interface{})
+		networkID, err := resolveInlineNetworkBlockID(block)
+		if err != nil {
+			continue
+		}
+		pn, ok := byNetwork[networkID]
+		if !ok || seen[networkID] {
+			continue
+		}
+		seen[networkID] = true
+		networks = append(networks, flattenServerPrivateNet(pn))
+	}
+
+	// Any attachment not matched to a configured block (e.g. one added
+	// out-of-band between applies) is appended in API order.
+	for i := range server.PrivateNet {
+		pn := &server.PrivateNet[i]
+		if seen[pn.Network.ID] {
+			continue
+		}
+		networks = append(networks, flattenServerPrivateNet(pn))
+	}
+
+	return networks
+}
+
+func flattenServerPrivateNet(pn *hcloud.ServerPrivateNet) map[string]interface{} {
+	aliasIPs := make([]string, len(pn.Aliases))
+	for i, ip := range pn.Aliases {
+		aliasIPs[i] = ip.String()
+	}
+	return map[string]interface{}{
+		"network_id":  pn.Network.ID,
+		"ip":          pn.IP.String(),
+		"alias_ips":   aliasIPs,
+		"mac_address": pn.MACAddress,
+	}
+}
+
+func setServerSchema(d *schema.ResourceData, server *hcloud.Server) {
+	d.SetId(generateServerID(server))
+	d.Set("name", server.Name)
+	d.Set("server_type", server.ServerType.Name)
+	d.Set("location", server.Datacenter.Location.Name)
+	d.Set("datacenter", server.Datacenter.Name)
+	d.Set("backups", len(server.BackupWindow) > 0)
+	d.Set("delete_protection", server.Protection.Delete)
+	d.Set("labels", filterManagedServerLabels(server.Labels))
+	d.Set("status", server.Status)
+	d.Set("ipv4_address", server.PublicNet.IPv4.IP.String())
+	if server.PublicNet.IPv6.IP != nil {
+		d.Set("ipv6_address", server.PublicNet.IPv6.IP.String())
+	}
+
+	// Only set the network block from API state when the user is managing it
+	// inline; servers whose attachments are all managed via standalone
+	// hcloud_server_network resources should keep this list empty.
+	if _, ok := d.GetOk("network"); ok {
+		d.Set("network", flattenServerNetworks(d, server))
+	}
+}
+
+// filterManagedServerLabels strips the hcloud-router/* labels
+// applyServerNetworkRouter/withdrawServerNetworkRouter set directly on the
+// server from hcloud_server_network_router. Those are owned by whichever
+// hcloud_server_network resources have a router block, not by this
+// resource's "labels" config, so echoing them back into "labels" here would
+// make the two resources fight over the same keys on every apply.
+func filterManagedServerLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if strings.HasPrefix(k, routerLabelPrefix+"/") {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func generateServerID(server *hcloud.Server) string {
+	return strconv.FormatInt(server.ID, 10)
+}
+
+func lookupServerID(ctx context.Context, terraformID string, client *hcloud.Client) (*hcloud.Server, error) {
+	id, err := strconv.ParseInt(terraformID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	server, _, err := client.Server.GetByID(ctx, id)
+	return server, err
+}
@@ -8,14 +8,23 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hetznercloud/hcloud-go/hcloud"
 	"github.com/hetznercloud/terraform-provider-hcloud/internal/merge"
 )
 
+const (
+	defaultServerNetworkCreateTimeout = 10 * time.Minute
+	defaultServerNetworkUpdateTimeout = 10 * time.Minute
+	defaultServerNetworkDeleteTimeout = 10 * time.Minute
+	defaultServerNetworkReadTimeout   = 5 * time.Minute
+)
+
 func resourceServerNetwork() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceServerNetworkCreate,
@@ -25,6 +34,26 @@ func resourceServerNetwork() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			StateContext: schema.ImportStatePassthroughContext,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultServerNetworkCreateTimeout),
+			Update: schema.DefaultTimeout(defaultServerNetworkUpdateTimeout),
+			Delete: schema.DefaultTimeout(defaultServerNetworkDeleteTimeout),
+			Read:   schema.DefaultTimeout(defaultServerNetworkReadTimeout),
+		},
+		// SchemaVersion 1: hcloud-go v2 widened Server/Network IDs from int to
+		// int64 (Hetzner IDs outgrew 32-bit precision in Sept 2023). network_id
+		// and server_id are still schema.TypeInt - the SDK has no int64 variant -
+		// so the stored shape is unchanged. The upgrader passes version-0 state
+		// through as-is so it is accepted without a forced replacement; it does
+		// not rewrite any stored ID, including one the old client truncated.
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Type:    resourceServerNetworkV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceServerNetworkStateUpgradeV0,
+				Version: 0,
+			},
+		},
 		Schema: map[string]*schema.Schema{
 			"network_id": {
 				Type:     schema.TypeInt,
@@ -56,10 +85,70 @@ func resourceServerNetwork() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			// adopt must be set when the (server_id, network_id) pair is already
+			// attached, e.g. by a "network" block on the corresponding
+			// hcloud_server resource (see resource_hcloud_server.go). Without it
+			// resourceServerNetworkCreate refuses to manage an attachment it did
+			// not create, so a server only ever has one owner per network.
+			"adopt": {
+				Type:     schema.TypeBool,
+				Optional: true,
+			},
+			// router turns this attachment into a candidate gateway for the CIDRs
+			// in advertised_routes; see resource_hcloud_server_network_router.go.
+			"router": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"priority": {
+							Type:     schema.TypeInt,
+							Required: true,
+						},
+						"advertised_routes": {
+							Type:     schema.TypeList,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"failover_group": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+			},
+			"is_active_router": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
 		},
 	}
 }
 
+// resourceServerNetworkV0 is the pre-int64 schema, kept only so
+// resourceServerNetworkStateUpgradeV0 has something to decode old state against.
+func resourceServerNetworkV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"network_id":  {Type: schema.TypeInt, Optional: true},
+			"subnet_id":   {Type: schema.TypeString, Optional: true},
+			"server_id":   {Type: schema.TypeInt, Required: true},
+			"ip":          {Type: schema.TypeString, Computed: true, Optional: true},
+			"alias_ips":   {Type: schema.TypeSet, Elem: &schema.Schema{Type: schema.TypeString}, Optional: true},
+			"mac_address": {Type: schema.TypeString, Computed: true},
+		},
+	}
+}
+
+func resourceServerNetworkStateUpgradeV0(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+	// Nothing to transform: network_id/server_id are still stored as whatever
+	// the SDK's TypeInt serialized, the upgrade only bumps SchemaVersion so
+	// state written by older provider versions is accepted without a forced
+	// replacement.
+	return rawState, nil
+}
+
 func resourceServerNetworkCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	var aliasIPs []net.IP
 
@@ -80,20 +169,38 @@ func resourceServerNetworkCreate(ctx context.Context, d *schema.ResourceData, m
 		networkID = nwID
 	}
 
-	server := &hcloud.Server{ID: d.Get("server_id").(int)}
-	network := &hcloud.Network{ID: networkID.(int)}
+	server := &hcloud.Server{ID: int64(d.Get("server_id").(int))}
+	network := &hcloud.Network{ID: int64(networkID.(int))}
+
+	full, _, err := client.Server.GetByID(ctx, server.ID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if full != nil && !d.Get("adopt").(bool) {
+		for _, pn := range full.PrivateNet {
+			if pn.Network.ID == network.ID {
+				return diag.Errorf("server %d is already attached to network %d (likely via a \"network\" block on hcloud_server); set adopt = true to manage it with this resource instead", server.ID, network.ID)
+			}
+		}
+	}
 
 	for _, aliasIP := range d.Get("alias_ips").(*schema.Set).List() {
 		ip := net.ParseIP(aliasIP.(string))
 		aliasIPs = append(aliasIPs, ip)
 	}
 
-	err := attachServerToNetwork(ctx, client, server, network, ip, aliasIPs)
+	err = attachServerToNetwork(ctx, client, server, network, ip, aliasIPs, d.Timeout(schema.TimeoutCreate))
 	if err != nil {
 		return diag.FromErr(err)
 	}
 	d.SetId(generateServerNetworkID(server, network))
 
+	if router, ok := expandServerNetworkRouter(d); ok {
+		if err := applyServerNetworkRouter(ctx, client, server, network, router, d.Timeout(schema.TimeoutCreate)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	return resourceServerNetworkRead(ctx, d, m)
 }
 
@@ -127,11 +234,24 @@ func resourceServerNetworkUpdate(ctx context.Context, d *schema.ResourceData, m
 			ip := net.ParseIP(aliasIP.(string))
 			opts.AliasIPs = append(opts.AliasIPs, ip)
 		}
-		action, _, err := client.Server.ChangeAliasIPs(ctx, server, opts)
+		err := waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutUpdate), func() (*hcloud.Action, error) {
+			a, _, err := client.Server.ChangeAliasIPs(ctx, server, opts)
+			return a, err
+		})
 		if err != nil {
 			return diag.FromErr(err)
 		}
-		if err := waitForNetworkAction(ctx, client, action, network); err != nil {
+	}
+
+	if d.HasChange("router") {
+		router, ok := expandServerNetworkRouter(d)
+		if !ok {
+			if oldRouter, oldOk := expandServerNetworkRouterOld(d); oldOk {
+				if err := withdrawServerNetworkRouter(ctx, client, server, network, oldRouter, d.Timeout(schema.TimeoutUpdate)); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+		} else if err := applyServerNetworkRouter(ctx, client, server, network, router, d.Timeout(schema.TimeoutUpdate)); err != nil {
 			return diag.FromErr(err)
 		}
 	}
@@ -167,13 +287,21 @@ func resourceServerNetworkRead(ctx context.Context, d *schema.ResourceData, m in
 	}
 	d.SetId(generateServerNetworkID(server, network))
 	setServerNetworkSchema(d, server, network, privateNet)
-	return nil
 
+	if router, ok := expandServerNetworkRouter(d); ok {
+		active, err := reconcileServerNetworkRouter(ctx, client, network, router, d.Timeout(schema.TimeoutRead))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("is_active_router", active == server.ID)
+	} else {
+		d.Set("is_active_router", false)
+	}
+
+	return nil
 }
 
 func resourceServerNetworkDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var action *hcloud.Action
-
 	client := m.(*hcloud.Client)
 
 	server, network, _, err := lookupServerNetworkID(ctx, d.Id(), client)
@@ -183,16 +311,18 @@ func resourceServerNetworkDelete(ctx context.Context, d *schema.ResourceData, m
 		d.SetId("")
 		return nil
 	}
-	err = retry(defaultMaxRetries, func() error {
-		var err error
 
-		action, _, err = client.Server.DetachFromNetwork(ctx, server, hcloud.ServerDetachFromNetworkOpts{
+	if router, ok := expandServerNetworkRouter(d); ok {
+		if err := withdrawServerNetworkRouter(ctx, client, server, network, router, d.Timeout(schema.TimeoutDelete)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	err = waitForServerNetworkAction(ctx, client, d.Timeout(schema.TimeoutDelete), func() (*hcloud.Action, error) {
+		a, _, err := client.Server.DetachFromNetwork(ctx, server, hcloud.ServerDetachFromNetworkOpts{
 			Network: network,
 		})
-		if hcloud.IsError(err, hcloud.ErrorCodeConflict) || hcloud.IsError(err, hcloud.ErrorCodeLocked) {
-			return err
-		}
-		return abortRetry(err)
+		return a, err
 	})
 
 	if hcloud.IsError(err, hcloud.ErrorCodeNotFound) {
@@ -202,9 +332,6 @@ func resourceServerNetworkDelete(ctx context.Context, d *schema.ResourceData, m
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	if err := waitForNetworkAction(ctx, client, action, network); err != nil {
-		return diag.FromErr(err)
-	}
 
 	return nil
 }
@@ -237,26 +364,16 @@ func setServerNetworkSchema(d *schema.ResourceData, server *hcloud.Server, netwo
 	d.Set("server_id", server.ID)
 }
 
-func attachServerToNetwork(ctx context.Context, c *hcloud.Client, srv *hcloud.Server, nw *hcloud.Network, ip net.IP, aliasIPs []net.IP) error {
-	var a *hcloud.Action
-
+func attachServerToNetwork(ctx context.Context, c *hcloud.Client, srv *hcloud.Server, nw *hcloud.Network, ip net.IP, aliasIPs []net.IP, timeout time.Duration) error {
 	opts := hcloud.ServerAttachToNetworkOpts{
 		Network:  nw,
 		IP:       ip,
 		AliasIPs: aliasIPs,
 	}
 
-	err := retry(defaultMaxRetries, func() error {
-		var err error
-
-		a, _, err = c.Server.AttachToNetwork(ctx, srv, opts)
-		if hcloud.IsError(err, hcloud.ErrorCodeConflict) || hcloud.IsError(err, hcloud.ErrorCodeLocked) {
-			return err
-		}
-		if err != nil {
-			return abortRetry(err)
-		}
-		return nil
+	err := waitForServerNetworkAction(ctx, c, timeout, func() (*hcloud.Action, error) {
+		a, _, err := c.Server.AttachToNetwork(ctx, srv, opts)
+		return a, err
 	})
 	if hcloud.IsError(err, hcloud.ErrorCodeServerAlreadyAttached) {
 		log.Printf("[INFO] Server (%v) already attachted to network %v", srv.ID, nw.ID)
@@ -265,16 +382,101 @@ func attachServerToNetwork(ctx context.Context, c *hcloud.Client, srv *hcloud.Se
 	if err != nil {
 		return fmt.Errorf("attach server to network: %v", err)
 	}
-	if err := waitForNetworkAction(ctx, c, a, nw); err != nil {
-		return fmt.Errorf("attach server to network: %v", err)
-	}
 	return nil
 }
 
+// waitForServerNetworkAction submits a server<->network action and blocks
+// until it completes, re-submitting while the API reports the server or
+// network as busy instead of failing outright. It replaces the old
+// retry(defaultMaxRetries, ...) + waitForNetworkAction pairing with a single
+// resource.StateChangeConf-driven poller, modeled on the StateRefreshFunc
+// pattern used for OpenStack's network delete waiter: distinct pending/target
+// states, and a conflict/locked response is "pending" rather than fatal.
+// The submit and action-completion stages share a single timeout budget -
+// timeout bounds the whole call, not each stage - so a slow attach can't run
+// up to 2x the timeout configured on the resource's "timeouts" block.
+func waitForServerNetworkAction(ctx context.Context, c *hcloud.Client, timeout time.Duration, submit func() (*hcloud.Action, error)) error {
+	deadline := time.Now().Add(timeout)
+
+	submitConf := &resource.StateChangeConf{
+		Pending:    []string{"conflict"},
+		Target:     []string{"submitted", "noop"},
+		Timeout:    timeout,
+		Delay:      1 * time.Second,
+		MinTimeout: 2 * time.Second,
+		Refresh: func() (interface{}, string, error) {
+			action, err := submit()
+			switch {
+			case hcloud.IsError(err, hcloud.ErrorCodeConflict) || hcloud.IsError(err, hcloud.ErrorCodeLocked):
+				return struct{}{}, "conflict", nil
+			case err != nil:
+				return nil, "", err
+			case action == nil:
+				return struct{}{}, "noop", nil
+			default:
+				return action, "submitted", nil
+			}
+		},
+	}
+	raw, err := submitConf.WaitForStateContext(ctx)
+	if err != nil {
+		return err
+	}
+	action, ok := raw.(*hcloud.Action)
+	if !ok {
+		// action was a no-op (e.g. server already attached); nothing to poll.
+		return nil
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return fmt.Errorf("timeout waiting for action %d to complete", action.ID)
+	}
+
+	actionConf := &resource.StateChangeConf{
+		Pending:    []string{string(hcloud.ActionStatusRunning)},
+		Target:     []string{string(hcloud.ActionStatusSuccess)},
+		Timeout:    remaining,
+		Delay:      1 * time.Second,
+		MinTimeout: 2 * time.Second,
+		Refresh: func() (interface{}, string, error) {
+			current, _, err := c.Action.GetByID(ctx, action.ID)
+			if err != nil {
+				return nil, "", err
+			}
+			if current == nil {
+				return nil, "", fmt.Errorf("action %d not found", action.ID)
+			}
+			if current.Status == hcloud.ActionStatusError {
+				return nil, "", current.Error()
+			}
+			return current, string(current.Status), nil
+		},
+	}
+	_, err = actionConf.WaitForStateContext(ctx)
+	return err
+}
+
 func generateServerNetworkID(server *hcloud.Server, network *hcloud.Network) string {
 	return fmt.Sprintf("%d-%d", server.ID, network.ID)
 }
 
+// parseNetworkSubnetID parses a subnet_id of the form
+// "<network id>-<subnet ip range>" (as produced by subnetIDForIP in
+// data_source_hcloud_server_network.go) back into the owning network ID and
+// the subnet's IP range string.
+func parseNetworkSubnetID(id string) (int, string, error) {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("invalid subnet_id %q: expected \"<network id>-<subnet ip range>\"", id)
+	}
+	networkID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid subnet_id %q: %v", id, err)
+	}
+	return networkID, parts[1], nil
+}
+
 var errInvalidServerNetworkID = errors.New("invalid server network id")
 
 // lookupServerNetworkID parses the terraform server network record id and return the server, network and the ServerPrivateNet
@@ -293,7 +495,7 @@ func lookupServerNetworkID(ctx context.Context, terraformID string, client *hclo
 		return
 	}
 
-	serverID, err := strconv.Atoi(parts[0])
+	serverID, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
 		err = errInvalidServerNetworkID
 		return
@@ -309,7 +511,7 @@ func lookupServerNetworkID(ctx context.Context, terraformID string, client *hclo
 		return
 	}
 
-	networkID, err := strconv.Atoi(parts[1])
+	networkID, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
 		err = errInvalidServerNetworkID
 		return
@@ -0,0 +1,365 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+// Labels used to advertise a server's router candidacy to every other
+// hcloud_server_network instance sharing its failover_group. Terraform
+// resource instances cannot see each other's state directly, so the label
+// set on the server itself is the only shared registry the API gives us.
+// The label keys are namespaced by network ID so a server that is a router
+// candidate on several networks at once (the normal "one gateway routing
+// several private networks" case) gets one independent slot per network
+// instead of each hcloud_server_network overwriting the others' candidacy.
+const routerLabelPrefix = "hcloud-router"
+
+func routerFailoverGroupLabel(networkID int64) string {
+	return fmt.Sprintf("%s/%d/failover-group", routerLabelPrefix, networkID)
+}
+
+func routerPriorityLabel(networkID int64) string {
+	return fmt.Sprintf("%s/%d/priority", routerLabelPrefix, networkID)
+}
+
+type serverNetworkRouter struct {
+	priority         int
+	advertisedRoutes []string
+	failoverGroup    string
+}
+
+func expandServerNetworkRouter(d *schema.ResourceData) (serverNetworkRouter, bool) {
+	return expandServerNetworkRouterBlock(d.Get("router").([]interface{}))
+}
+
+// expandServerNetworkRouterOld reads the pre-apply value of the router block,
+// as opposed to expandServerNetworkRouter which reads the post-apply value.
+// resourceServerNetworkUpdate needs the old value to withdraw a candidacy
+// that a config change is removing: by the time Update runs, d.Get("router")
+// already reflects the new (empty) config, so re-deriving the withdrawn
+// candidate's failover_group/advertised_routes from d would withdraw nothing.
+func expandServerNetworkRouterOld(d *schema.ResourceData) (serverNetworkRouter, bool) {
+	old, _ := d.GetChange("router")
+	return expandServerNetworkRouterBlock(old.([]interface{}))
+}
+
+func expandServerNetworkRouterBlock(raw []interface{}) (serverNetworkRouter, bool) {
+	if len(raw) == 0 || raw[0] == nil {
+		return serverNetworkRouter{}, false
+	}
+	block := raw[0].(map[string]interface{})
+
+	routes := make([]string, 0, len(block["advertised_routes"].([]interface{})))
+	for _, r := range block["advertised_routes"].([]interface{}) {
+		routes = append(routes, r.(string))
+	}
+
+	return serverNetworkRouter{
+		priority:         block["priority"].(int),
+		advertisedRoutes: routes,
+		failoverGroup:    block["failover_group"].(string),
+	}, true
+}
+
+// applyServerNetworkRouter labels srv as a candidate gateway for router's
+// failover_group and reconciles the network's routes so the
+// highest-priority healthy candidate is the active gateway.
+func applyServerNetworkRouter(ctx context.Context, client *hcloud.Client, srv *hcloud.Server, network *hcloud.Network, router serverNetworkRouter, timeout time.Duration) error {
+	unlock := lockServerNetworkRouterLabels(srv.ID)
+	full, _, err := client.Server.GetByID(ctx, srv.ID)
+	if err != nil {
+		unlock()
+		return fmt.Errorf("router: %v", err)
+	}
+	if full == nil {
+		unlock()
+		return fmt.Errorf("router: server %d not found", srv.ID)
+	}
+
+	labels := make(map[string]string, len(full.Labels)+2)
+	for k, v := range full.Labels {
+		labels[k] = v
+	}
+	labels[routerFailoverGroupLabel(network.ID)] = router.failoverGroup
+	labels[routerPriorityLabel(network.ID)] = strconv.Itoa(router.priority)
+
+	_, _, err = client.Server.Update(ctx, full, hcloud.ServerUpdateOpts{Labels: labels})
+	unlock()
+	if err != nil {
+		return fmt.Errorf("router: label server %d: %v", srv.ID, err)
+	}
+
+	return reconcileRouteTable(ctx, client, network, router.failoverGroup, router.advertisedRoutes, timeout)
+}
+
+// withdrawServerNetworkRouter removes srv's router candidacy labels for
+// network and promotes the next-highest-priority healthy candidate in its
+// place. router must be the candidacy being withdrawn (the resource's old
+// value, not whatever is currently in config) since it carries the
+// failover_group/advertised_routes needed to reconcile the route table.
+func withdrawServerNetworkRouter(ctx context.Context, client *hcloud.Client, srv *hcloud.Server, network *hcloud.Network, router serverNetworkRouter, timeout time.Duration) error {
+	unlock := lockServerNetworkRouterLabels(srv.ID)
+	full, _, err := client.Server.GetByID(ctx, srv.ID)
+	if err != nil {
+		unlock()
+		return fmt.Errorf("router: %v", err)
+	}
+	if full == nil {
+		unlock()
+		// Server is already gone; nothing to unlabel, but the group still
+		// needs to re-elect since this candidate can no longer serve.
+		return reconcileRouteTable(ctx, client, network, router.failoverGroup, router.advertisedRoutes, timeout)
+	}
+
+	failoverGroupLabel := routerFailoverGroupLabel(network.ID)
+	priorityLabel := routerPriorityLabel(network.ID)
+
+	labels := make(map[string]string, len(full.Labels))
+	for k, v := range full.Labels {
+		if k == failoverGroupLabel || k == priorityLabel {
+			continue
+		}
+		labels[k] = v
+	}
+	_, _, err = client.Server.Update(ctx, full, hcloud.ServerUpdateOpts{Labels: labels})
+	unlock()
+	if err != nil {
+		return fmt.Errorf("router: unlabel server %d: %v", srv.ID, err)
+	}
+
+	return reconcileRouteTable(ctx, client, network, router.failoverGroup, router.advertisedRoutes, timeout)
+}
+
+// reconcileServerNetworkRouter is the read-path entry point. It reconciles
+// network's route table against the currently elected gateway for
+// failoverGroup, then returns that gateway's server ID for is_active_router.
+// A plain `terraform plan`/refresh calls this, so a failover that happened
+// outside of an apply (e.g. the active gateway going down) is actually
+// reprogrammed on the next refresh instead of only being reflected in the
+// computed attribute - reconcileRouteTable is itself idempotent, so a Read
+// that finds nothing to change is a no-op.
+func reconcileServerNetworkRouter(ctx context.Context, client *hcloud.Client, network *hcloud.Network, router serverNetworkRouter, timeout time.Duration) (int64, error) {
+	if err := reconcileRouteTable(ctx, client, network, router.failoverGroup, router.advertisedRoutes, timeout); err != nil {
+		return 0, err
+	}
+	active, _, err := electRouteGateway(ctx, client, network, router.failoverGroup)
+	if err != nil {
+		return 0, err
+	}
+	if active == nil {
+		return 0, nil
+	}
+	return active.server.ID, nil
+}
+
+type routerCandidate struct {
+	server *hcloud.Server
+	ip     net.IP
+}
+
+// listRouterCandidates lists every server labeled into failoverGroup on
+// network, regardless of health - electRouteGateway narrows this down to the
+// elected gateway, reconcileRouteTable also uses the raw list to recognize
+// which existing routes it owns.
+func listRouterCandidates(ctx context.Context, client *hcloud.Client, network *hcloud.Network, failoverGroup string) ([]*hcloud.Server, error) {
+	servers, err := client.Server.AllWithOpts(ctx, hcloud.ServerListOpts{
+		ListOpts: hcloud.ListOpts{
+			LabelSelector: fmt.Sprintf("%s==%s", routerFailoverGroupLabel(network.ID), failoverGroup),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("router: list candidates for group %q: %v", failoverGroup, err)
+	}
+	return servers, nil
+}
+
+// electRouteGateway lists every server labeled into failoverGroup and
+// delegates the actual candidate selection to selectRouteGateway.
+func electRouteGateway(ctx context.Context, client *hcloud.Client, network *hcloud.Network, failoverGroup string) (*routerCandidate, int, error) {
+	servers, err := listRouterCandidates(ctx, client, network, failoverGroup)
+	if err != nil {
+		return nil, 0, err
+	}
+	best, candidateCount := selectRouteGateway(servers, network.ID)
+	return best, candidateCount, nil
+}
+
+// selectRouteGateway discards servers not running or not attached to
+// networkID, and returns the highest-priority survivor (ties broken by
+// lowest server ID, for a deterministic result across concurrent callers),
+// plus the total number of healthy candidates considered. Split out of
+// electRouteGateway so the selection logic can be unit tested against a
+// hand-built server list, without a live API client.
+func selectRouteGateway(servers []*hcloud.Server, networkID int64) (*routerCandidate, int) {
+	var best *routerCandidate
+	bestPriority := -1
+	candidateCount := 0
+
+	for _, srv := range servers {
+		if srv.Status != hcloud.ServerStatusRunning {
+			continue
+		}
+		var ip net.IP
+		for _, pn := range srv.PrivateNet {
+			if pn.Network.ID == networkID {
+				ip = pn.IP
+				break
+			}
+		}
+		if ip == nil {
+			continue
+		}
+		candidateCount++
+
+		priority, _ := strconv.Atoi(srv.Labels[routerPriorityLabel(networkID)])
+		if best == nil || priority > bestPriority || (priority == bestPriority && srv.ID < best.server.ID) {
+			best = &routerCandidate{server: srv, ip: ip}
+			bestPriority = priority
+		}
+	}
+
+	return best, candidateCount
+}
+
+// reconcileRouteTable programs network's routes so every advertised CIDR
+// points at the currently elected gateway, adding or replacing routes as
+// needed, and deletes routes this failover_group previously advertised but
+// no longer does (e.g. a CIDR dropped from advertised_routes). timeout
+// bounds every AddRoute/DeleteRoute wait, same as the resource's own
+// configured timeout would for any other action. It is serialized per
+// network so concurrent applies from several hcloud_server_network
+// resources in the same failover_group don't race each other's
+// AddRoute/DeleteRoute calls.
+func reconcileRouteTable(ctx context.Context, client *hcloud.Client, network *hcloud.Network, failoverGroup string, advertisedRoutes []string, timeout time.Duration) error {
+	unlock := lockServerNetworkRouter(network.ID)
+	defer unlock()
+
+	candidates, err := listRouterCandidates(ctx, client, network, failoverGroup)
+	if err != nil {
+		return err
+	}
+	active, candidateCount := selectRouteGateway(candidates, network.ID)
+	if candidateCount == 0 {
+		// No healthy candidate left (group draining or all unhealthy); leave
+		// existing routes in place rather than blackholing traffic.
+		return nil
+	}
+
+	full, _, err := client.Network.GetByID(ctx, network.ID)
+	if err != nil {
+		return fmt.Errorf("router: %v", err)
+	}
+	if full == nil {
+		return fmt.Errorf("router: network %d not found", network.ID)
+	}
+
+	wanted := make(map[string]bool, len(advertisedRoutes))
+	for _, cidr := range advertisedRoutes {
+		wanted[cidr] = true
+	}
+
+	// A route is this failover_group's to prune if its gateway is one of the
+	// group's own candidates (current or former priority holders still
+	// attached to network) - anything else might belong to a different
+	// failover_group sharing the network, or to a route the user manages by
+	// hand, and reconcileRouteTable must leave those alone.
+	candidateIPs := make(map[string]bool, len(candidates))
+	for _, srv := range candidates {
+		for _, pn := range srv.PrivateNet {
+			if pn.Network.ID == network.ID {
+				candidateIPs[pn.IP.String()] = true
+			}
+		}
+	}
+	for i := range full.Routes {
+		route := full.Routes[i]
+		if wanted[route.Destination.String()] || !candidateIPs[route.Gateway.String()] {
+			continue
+		}
+		action, _, err := client.Network.DeleteRoute(ctx, full, hcloud.NetworkDeleteRouteOpts{Route: route})
+		if err != nil {
+			return fmt.Errorf("router: delete dropped route for %s: %v", route.Destination.String(), err)
+		}
+		if err := waitForServerNetworkAction(ctx, client, timeout, func() (*hcloud.Action, error) { return action, nil }); err != nil {
+			return fmt.Errorf("router: delete dropped route for %s: %v", route.Destination.String(), err)
+		}
+	}
+
+	for _, cidr := range advertisedRoutes {
+		_, dest, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("router: invalid advertised_routes entry %q: %v", cidr, err)
+		}
+
+		var existing *hcloud.NetworkRoute
+		for i := range full.Routes {
+			if full.Routes[i].Destination.String() == dest.String() {
+				existing = &full.Routes[i]
+				break
+			}
+		}
+
+		if routeMatchesGateway(existing, active.ip) {
+			continue
+		}
+		if existing != nil {
+			action, _, err := client.Network.DeleteRoute(ctx, full, hcloud.NetworkDeleteRouteOpts{Route: *existing})
+			if err != nil {
+				return fmt.Errorf("router: delete stale route for %s: %v", cidr, err)
+			}
+			if err := waitForServerNetworkAction(ctx, client, timeout, func() (*hcloud.Action, error) { return action, nil }); err != nil {
+				return fmt.Errorf("router: delete stale route for %s: %v", cidr, err)
+			}
+		}
+
+		action, _, err := client.Network.AddRoute(ctx, full, hcloud.NetworkAddRouteOpts{
+			Route: hcloud.NetworkRoute{Destination: dest, Gateway: active.ip},
+		})
+		if err != nil {
+			return fmt.Errorf("router: add route for %s via %s: %v", cidr, active.ip, err)
+		}
+		if err := waitForServerNetworkAction(ctx, client, timeout, func() (*hcloud.Action, error) { return action, nil }); err != nil {
+			return fmt.Errorf("router: add route for %s via %s: %v", cidr, active.ip, err)
+		}
+	}
+	return nil
+}
+
+// routeMatchesGateway reports whether existing already points at
+// desiredGateway, i.e. reconcileRouteTable has nothing to do for this route.
+func routeMatchesGateway(existing *hcloud.NetworkRoute, desiredGateway net.IP) bool {
+	return existing != nil && existing.Gateway.Equal(desiredGateway)
+}
+
+var serverNetworkRouterLocks sync.Map // map[int64]*sync.Mutex
+
+func lockServerNetworkRouter(networkID int64) func() {
+	v, _ := serverNetworkRouterLocks.LoadOrStore(networkID, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// serverNetworkRouterLabelLocks guards the read-modify-write of a server's
+// labels in applyServerNetworkRouter/withdrawServerNetworkRouter, keyed on
+// server ID rather than network ID: a server being a router candidate on
+// several networks at once is the normal case this feature targets, and
+// without this lock two hcloud_server_network resources for the same server
+// but different networks/failover_groups can race each other's GetByID ->
+// modify -> Update round trip and clobber each other's candidacy label.
+var serverNetworkRouterLabelLocks sync.Map // map[int64]*sync.Mutex
+
+func lockServerNetworkRouterLabels(serverID int64) func() {
+	v, _ := serverNetworkRouterLabelLocks.LoadOrStore(serverID, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,101 @@
+package hcloud
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+func serverInNetwork(id int64, status hcloud.ServerStatus, networkID int64, ip string, priority string) *hcloud.Server {
+	return &hcloud.Server{
+		ID:     id,
+		Status: status,
+		Labels: map[string]string{routerPriorityLabel(networkID): priority},
+		PrivateNet: []hcloud.ServerPrivateNet{
+			{
+				Network: &hcloud.Network{ID: networkID},
+				IP:      net.ParseIP(ip),
+			},
+		},
+	}
+}
+
+func TestSelectRouteGateway(t *testing.T) {
+	const networkID = 1
+
+	t.Run("picks the highest priority healthy candidate", func(t *testing.T) {
+		servers := []*hcloud.Server{
+			serverInNetwork(1, hcloud.ServerStatusRunning, networkID, "10.0.0.1", "10"),
+			serverInNetwork(2, hcloud.ServerStatusRunning, networkID, "10.0.0.2", "20"),
+			serverInNetwork(3, hcloud.ServerStatusRunning, networkID, "10.0.0.3", "5"),
+		}
+
+		best, count := selectRouteGateway(servers, networkID)
+		if count != 3 {
+			t.Fatalf("candidateCount = %d, want 3", count)
+		}
+		if best == nil || best.server.ID != 2 {
+			t.Fatalf("best = %+v, want server 2", best)
+		}
+	})
+
+	t.Run("ties break on lowest server ID", func(t *testing.T) {
+		servers := []*hcloud.Server{
+			serverInNetwork(5, hcloud.ServerStatusRunning, networkID, "10.0.0.5", "10"),
+			serverInNetwork(2, hcloud.ServerStatusRunning, networkID, "10.0.0.2", "10"),
+		}
+
+		best, _ := selectRouteGateway(servers, networkID)
+		if best == nil || best.server.ID != 2 {
+			t.Fatalf("best = %+v, want server 2", best)
+		}
+	})
+
+	t.Run("skips servers that are not running", func(t *testing.T) {
+		servers := []*hcloud.Server{
+			serverInNetwork(1, hcloud.ServerStatusOff, networkID, "10.0.0.1", "100"),
+			serverInNetwork(2, hcloud.ServerStatusRunning, networkID, "10.0.0.2", "1"),
+		}
+
+		best, count := selectRouteGateway(servers, networkID)
+		if count != 1 {
+			t.Fatalf("candidateCount = %d, want 1", count)
+		}
+		if best == nil || best.server.ID != 2 {
+			t.Fatalf("best = %+v, want server 2", best)
+		}
+	})
+
+	t.Run("skips servers not attached to the network", func(t *testing.T) {
+		servers := []*hcloud.Server{
+			serverInNetwork(1, hcloud.ServerStatusRunning, 999, "10.0.0.1", "100"),
+		}
+
+		best, count := selectRouteGateway(servers, networkID)
+		if count != 0 || best != nil {
+			t.Fatalf("got best=%+v count=%d, want no candidates", best, count)
+		}
+	})
+
+	t.Run("no candidates", func(t *testing.T) {
+		best, count := selectRouteGateway(nil, networkID)
+		if best != nil || count != 0 {
+			t.Fatalf("got best=%+v count=%d, want no candidates", best, count)
+		}
+	})
+}
+
+func TestRouteMatchesGateway(t *testing.T) {
+	gw := net.ParseIP("10.0.0.2")
+
+	if routeMatchesGateway(nil, gw) {
+		t.Fatal("nil existing route should never match")
+	}
+	if routeMatchesGateway(&hcloud.NetworkRoute{Gateway: net.ParseIP("10.0.0.3")}, gw) {
+		t.Fatal("different gateway should not match")
+	}
+	if !routeMatchesGateway(&hcloud.NetworkRoute{Gateway: gw}, gw) {
+		t.Fatal("identical gateway should match")
+	}
+}
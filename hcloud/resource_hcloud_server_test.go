@@ -0,0 +1,69 @@
+package hcloud
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func networkBlock(networkID int) map[string]interface{} {
+	return map[string]interface{}{"network_id": networkID}
+}
+
+func sortedInt64s(ids []int64) []int64 {
+	out := append([]int64(nil), ids...)
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func TestDiffInlineServerNetworks(t *testing.T) {
+	oldBlocks := map[int64]map[string]interface{}{
+		1: networkBlock(1),
+		2: networkBlock(2),
+	}
+	newBlocks := map[int64]map[string]interface{}{
+		2: networkBlock(2),
+		3: networkBlock(3),
+	}
+
+	diff := diffInlineServerNetworks(oldBlocks, newBlocks)
+
+	if got := sortedInt64s(diff.detach); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("detach = %v, want [1]", got)
+	}
+	if got := sortedInt64s(diff.attach); !reflect.DeepEqual(got, []int64{3}) {
+		t.Errorf("attach = %v, want [3]", got)
+	}
+	if got := sortedInt64s(diff.update); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("update = %v, want [2]", got)
+	}
+}
+
+func TestDiffInlineServerNetworksEmpty(t *testing.T) {
+	diff := diffInlineServerNetworks(nil, nil)
+	if len(diff.detach) != 0 || len(diff.attach) != 0 || len(diff.update) != 0 {
+		t.Fatalf("expected no changes, got %+v", diff)
+	}
+}
+
+func TestValidateServerNetworkBlock(t *testing.T) {
+	cases := []struct {
+		name    string
+		block   map[string]interface{}
+		wantErr bool
+	}{
+		{"network_id only", map[string]interface{}{"network_id": 1, "subnet_id": ""}, false},
+		{"subnet_id only", map[string]interface{}{"network_id": 0, "subnet_id": "1-10.0.0.0/24"}, false},
+		{"neither set", map[string]interface{}{"network_id": 0, "subnet_id": ""}, true},
+		{"both set", map[string]interface{}{"network_id": 1, "subnet_id": "1-10.0.0.0/24"}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateServerNetworkBlock(tc.block)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateServerNetworkBlock(%+v) err = %v, wantErr %v", tc.block, err, tc.wantErr)
+			}
+		})
+	}
+}